@@ -22,6 +22,7 @@ package anser
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/mongodb/amboy"
@@ -52,8 +53,30 @@ import (
 type Application struct {
 	Generators []Generator
 	Options    model.ApplicationOptions
-	env        Environment
-	hasSetup   bool
+	// Locker, if set, is acquired before Run enqueues any
+	// generators and released when Run returns. It is used to
+	// prevent concurrent anser processes from running the same
+	// migration against the same database, which is otherwise a
+	// common footgun during rolling deploys. LockName identifies
+	// the lock to acquire and defaults to "anser" if unset.
+	Locker   MigrationLocker
+	LockName string
+	// AppliedLog, if set, records which generators Run has actually
+	// completed. Run consults it to skip generators it has already
+	// applied, and Rollback consults it to determine what is safe to
+	// undo. Without it, Rollback refuses to run.
+	AppliedLog AppliedMigrationLog
+	// Observer, if set, receives structured callbacks as Run moves
+	// through its phases. If unset, Run uses a default observer that
+	// logs each phase transition, generator and migration job
+	// through grip.
+	Observer RunObserver
+
+	env      Environment
+	hasSetup bool
+
+	generatorCancelsMu sync.Mutex
+	generatorCancels   map[string]context.CancelFunc
 }
 
 // Setup takes a configured anser.Environment implementation and
@@ -90,41 +113,180 @@ func (a *Application) Run(ctx context.Context) error {
 		return errors.Wrap(err, "getting queue")
 	}
 
-	catcher := grip.NewCatcher()
-	// iterate through generators
-	for _, generator := range a.Generators {
-		catcher.Add(queue.Put(ctx, generator))
-	}
+	if a.Locker != nil {
+		lockName := a.LockName
+		if lockName == "" {
+			lockName = "anser"
+		}
 
-	if catcher.HasErrors() {
-		return errors.Wrap(catcher.Resolve(), "adding generation jobs")
-	}
+		if a.Options.LockMode == model.LockModeError {
+			if err := a.tryAcquireLock(ctx, lockName); err != nil {
+				return errors.WithStack(err)
+			}
+		} else if err := a.Locker.Acquire(ctx, lockName); err != nil {
+			return errors.Wrap(err, "acquiring migration lock")
+		}
 
-	amboy.WaitInterval(ctx, queue, time.Second)
-	if ctx.Err() != nil {
-		return errors.New("migration operation canceled")
+		defer func() {
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			grip.Error(errors.Wrap(a.Locker.Release(releaseCtx, lockName), "releasing migration lock"))
+		}()
 	}
 
-	numMigrations, err := addMigrationJobs(ctx, queue, a.Options.DryRun, a.Options.Limit)
+	pending, err := a.pendingGenerators(ctx)
 	if err != nil {
-		return errors.Wrap(err, "adding generated migration jobs")
+		return errors.Wrap(err, "determining already-applied generators")
+	}
+
+	observer := a.observer()
+
+	observer.OnPhaseTransition(ctx, PhaseGeneration)
+
+	batches := batchGenerators(pending, a.Options.MaxConcurrentGenerators)
+
+	var totalMigrations int
+	limited := a.Options.Limit > 0
+	remainingLimit := a.Options.Limit
+
+	for _, batch := range batches {
+		if err := waitForMigrationCapacity(ctx, queue, a.Options.MaxConcurrentMigrations); err != nil {
+			return errors.Wrap(err, "waiting for migration queue capacity")
+		}
+
+		catcher := grip.NewCatcher()
+		for _, generator := range batch {
+			genCtx := a.generatorContext(ctx, generator.ID())
+			observer.OnGeneratorStart(genCtx, generator.ID())
+			catcher.Add(queue.Put(genCtx, generator))
+		}
+
+		if catcher.HasErrors() {
+			return errors.Wrap(catcher.Resolve(), "adding generation jobs")
+		}
+
+		amboy.WaitInterval(ctx, queue, time.Second)
+		if ctx.Err() != nil {
+			return errors.New("migration operation canceled")
+		}
+
+		for _, generator := range batch {
+			job, ok := queue.Get(ctx, generator.ID())
+			var genErr error
+			if ok {
+				genErr = job.Error()
+			}
+			observer.OnGeneratorComplete(ctx, generator.ID(), genErr)
+			a.clearGeneratorContext(generator.ID())
+		}
+
+		// limited && remainingLimit == 0 means the configured Limit is
+		// already exhausted. 0 is the sentinel addMigrationJobs (like
+		// Options.Limit itself) treats as "unlimited", so once we hit
+		// it we must stop calling addMigrationJobs entirely rather
+		// than pass it 0 and accidentally uncap the remaining batches.
+		var numMigrations int
+		if !limited || remainingLimit > 0 {
+			var err error
+			numMigrations, err = addMigrationJobs(ctx, queue, a.Options.DryRun, remainingLimit)
+			if err != nil {
+				return errors.Wrap(err, "adding generated migration jobs")
+			}
+		}
+		totalMigrations += numMigrations
+		if limited {
+			remainingLimit -= numMigrations
+			if remainingLimit < 0 {
+				remainingLimit = 0
+			}
+		}
+
+		if a.Options.DryRun {
+			continue
+		}
+
+		grip.Infof("added %d migration jobs for a batch of %d generators", numMigrations, len(batch))
+
+		// Drain this batch's migration jobs down to
+		// MaxConcurrentMigrations before the next batch of
+		// generators is allowed to fan out into more jobs.
+		if err := waitForMigrationCapacity(ctx, queue, a.Options.MaxConcurrentMigrations); err != nil {
+			return errors.Wrap(err, "waiting for migration queue capacity")
+		}
 	}
 
 	if a.Options.DryRun {
-		grip.Noticef("ending dry run, generated %d jobs in %d migrations", numMigrations, len(a.Generators))
+		grip.Noticef("ending dry run, generated %d jobs in %d migrations", totalMigrations, len(pending))
+		observer.OnPhaseTransition(ctx, PhaseComplete)
 		return nil
 	}
 
-	grip.Infof("added %d migration jobs from %d migrations", numMigrations, len(a.Generators))
-	grip.Noticef("waiting for %d migration jobs of %d migrations", numMigrations, len(a.Generators))
+	observer.OnPhaseTransition(ctx, PhaseExecution)
+	grip.Noticef("waiting for %d migration jobs of %d migrations", totalMigrations, len(pending))
 	amboy.WaitInterval(ctx, queue, time.Second)
 	if ctx.Err() != nil {
 		return errors.New("migration operation canceled")
 	}
 
+	generatorIDs := make(map[string]bool, len(pending))
+	for _, generator := range pending {
+		generatorIDs[generator.ID()] = true
+	}
+
+	// queue.Results reports every job the queue has tracked,
+	// including the generation jobs (the generators themselves)
+	// already reported individually above via OnGeneratorComplete;
+	// skip those so OnMigrationJobComplete only fires for the
+	// migration jobs the generators produced.
+	for job := range queue.Results(ctx) {
+		if generatorIDs[job.ID()] {
+			continue
+		}
+		observer.OnMigrationJobComplete(ctx, job.ID(), job.Error())
+	}
+
 	if err := amboy.ResolveErrors(ctx, queue); err != nil {
 		return errors.Wrap(err, "running migration jobs")
 	}
 
+	if a.AppliedLog != nil {
+		recordCatcher := grip.NewCatcher()
+		for _, generator := range pending {
+			recordCatcher.Add(a.AppliedLog.Record(ctx, generator.ID()))
+		}
+		if recordCatcher.HasErrors() {
+			return errors.Wrap(recordCatcher.Resolve(), "updating applied migration log")
+		}
+	}
+
+	observer.OnPhaseTransition(ctx, PhaseComplete)
 	return nil
 }
+
+// pendingGenerators returns the subset of a.Generators that have not
+// already been recorded in a.AppliedLog, so that re-running Run is
+// idempotent. If a.AppliedLog is unset, all generators are pending.
+func (a *Application) pendingGenerators(ctx context.Context) ([]Generator, error) {
+	if a.AppliedLog == nil {
+		return a.Generators, nil
+	}
+
+	applied, err := a.AppliedLog.Applied(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	appliedSet := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		appliedSet[id] = true
+	}
+
+	pending := make([]Generator, 0, len(a.Generators))
+	for _, generator := range a.Generators {
+		if !appliedSet[generator.ID()] {
+			pending = append(pending, generator)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,22 @@
+package anser
+
+import "context"
+
+// AppliedMigrationLog records which generators have actually been run
+// to completion by Application.Run, as distinct from the set of
+// Generators an Application happens to be configured with. Run
+// consults the log to skip generators it has already applied, which
+// makes re-running Run idempotent, and Rollback consults it to
+// determine what is safe to undo and in what order.
+type AppliedMigrationLog interface {
+	// Applied returns the IDs of generators that have been recorded
+	// as applied, in the order they were recorded.
+	Applied(ctx context.Context) ([]string, error)
+
+	// Record marks a generator ID as applied.
+	Record(ctx context.Context, id string) error
+
+	// Remove clears a generator ID's applied record, for use after
+	// its inverse has been run during a Rollback.
+	Remove(ctx context.Context, id string) error
+}
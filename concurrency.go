@@ -0,0 +1,95 @@
+package anser
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/amboy"
+	"github.com/pkg/errors"
+)
+
+// QueueDepth reports the number of jobs currently pending or running
+// on the configured Environment's queue. Callers can poll this during
+// a long Run to build a throughput or ETA display; it is also what
+// waitForMigrationCapacity uses internally to enforce
+// ApplicationOptions.MaxConcurrentMigrations.
+func (a *Application) QueueDepth(ctx context.Context) (int, error) {
+	queue, err := a.env.GetQueue()
+	if err != nil {
+		return 0, errors.Wrap(err, "getting queue")
+	}
+
+	stats := queue.Stats(ctx)
+	return stats.Pending + stats.Running, nil
+}
+
+// waitForMigrationCapacity blocks, polling the queue's depth, until
+// fewer than max jobs are pending or running, so that a batch of
+// generators does not blow past MaxConcurrentMigrations when it fans
+// out into migration jobs. A max of 0 or less is treated as no limit.
+func waitForMigrationCapacity(ctx context.Context, queue amboy.Queue, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		stats := queue.Stats(ctx)
+		if stats.Pending+stats.Running < max {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "canceled waiting for migration queue capacity")
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// batchGenerators splits generators into contiguous batches of at
+// most size generators each. A size less than or equal to 0 puts all
+// generators in a single batch, preserving the historical behavior of
+// enqueuing every generator at once.
+func batchGenerators(generators []Generator, size int) [][]Generator {
+	bounds := batchBounds(len(generators), size)
+	if bounds == nil {
+		return nil
+	}
+
+	batches := make([][]Generator, 0, len(bounds))
+	for _, b := range bounds {
+		batches = append(batches, generators[b[0]:b[1]])
+	}
+
+	return batches
+}
+
+// batchBounds computes the [start, end) index pairs that split a
+// slice of length n into contiguous batches of at most size elements
+// each. It contains no Generator-specific logic so that the batching
+// math can be unit tested directly. A size less than or equal to 0
+// produces a single batch spanning all of n.
+func batchBounds(n, size int) [][2]int {
+	if n == 0 {
+		return nil
+	}
+
+	if size <= 0 {
+		size = n
+	}
+
+	bounds := make([][2]int, 0, (n+size-1)/size)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		bounds = append(bounds, [2]int{start, end})
+	}
+
+	return bounds
+}
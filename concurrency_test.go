@@ -0,0 +1,38 @@
+package anser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatchBoundsUnlimitedSizeIsSingleBatch(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		got := batchBounds(5, size)
+		want := [][2]int{{0, 5}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("size %d: expected %v, got %v", size, want, got)
+		}
+	}
+}
+
+func TestBatchBoundsSplitsIntoChunks(t *testing.T) {
+	got := batchBounds(5, 2)
+	want := [][2]int{{0, 2}, {2, 4}, {4, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBatchBoundsExactMultiple(t *testing.T) {
+	got := batchBounds(6, 2)
+	want := [][2]int{{0, 2}, {2, 4}, {4, 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBatchBoundsEmptyInput(t *testing.T) {
+	if got := batchBounds(0, 2); got != nil {
+		t.Fatalf("expected no batches for empty input, got %v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package anser
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// jsonProgressObserver writes one jsonProgressEvent per line to an
+// underlying io.Writer, suitable for consumption by a supervising
+// process that wants a live, machine-readable progress stream for a
+// long-running migration.
+type jsonProgressObserver struct {
+	noopObserver
+
+	mu               sync.Mutex
+	out              io.Writer
+	enc              *json.Encoder
+	generatorStarted map[string]time.Time
+	generatorsDone   jobCounts
+	jobsDone         jobCounts
+}
+
+// NewJSONProgressObserver returns a RunObserver that emits a JSON
+// object per line to w for every phase transition, generator, and
+// migration job event. Writes are serialized, so w need not be safe
+// for concurrent use.
+func NewJSONProgressObserver(w io.Writer) RunObserver {
+	return &jsonProgressObserver{
+		out:              w,
+		enc:              json.NewEncoder(w),
+		generatorStarted: map[string]time.Time{},
+	}
+}
+
+func (o *jsonProgressObserver) write(event jsonProgressEvent) {
+	event.Time = time.Now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// encoding errors here are not actionable by the caller and
+	// would otherwise have nowhere to go, since RunObserver methods
+	// do not return an error.
+	_ = o.enc.Encode(event)
+}
+
+func (o *jsonProgressObserver) OnPhaseTransition(_ context.Context, phase RunPhase) {
+	o.write(jsonProgressEvent{Phase: phase, Event: "phase_transition"})
+}
+
+func (o *jsonProgressObserver) OnGeneratorStart(_ context.Context, generatorID string) {
+	o.mu.Lock()
+	o.generatorStarted[generatorID] = time.Now()
+	o.mu.Unlock()
+
+	o.write(jsonProgressEvent{GeneratorID: generatorID, Event: "generator_start"})
+}
+
+func (o *jsonProgressObserver) OnGeneratorComplete(_ context.Context, generatorID string, err error) {
+	o.mu.Lock()
+	elapsed := time.Since(o.generatorStarted[generatorID])
+	delete(o.generatorStarted, generatorID)
+	counts := o.generatorsDone.record(err == nil)
+	o.mu.Unlock()
+
+	event := jsonProgressEvent{
+		GeneratorID: generatorID,
+		Event:       "generator_complete",
+		Elapsed:     elapsed,
+		Succeeded:   counts.Succeeded,
+		Failed:      counts.Failed,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	o.write(event)
+}
+
+func (o *jsonProgressObserver) OnMigrationJobComplete(_ context.Context, jobID string, err error) {
+	o.mu.Lock()
+	counts := o.jobsDone.record(err == nil)
+	o.mu.Unlock()
+
+	event := jsonProgressEvent{
+		JobID:     jobID,
+		Event:     "migration_job_complete",
+		Succeeded: counts.Succeeded,
+		Failed:    counts.Failed,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	o.write(event)
+}
@@ -0,0 +1,52 @@
+package model
+
+// LockMode controls how Application.Run behaves when it cannot
+// immediately acquire the advisory lock for a migration.
+type LockMode string
+
+const (
+	// LockModeBlock causes Run to wait, retrying with backoff, until
+	// the lock is acquired or the context is canceled.
+	LockModeBlock LockMode = "block"
+
+	// LockModeError causes Run to return ErrLockHeld if the lock is
+	// already held by another process, without retrying. This is
+	// truly immediate when the configured MigrationLocker implements
+	// anser.LockTryAcquirer; otherwise Run falls back to waiting up
+	// to a short, fixed timeout before giving up.
+	LockModeError LockMode = "error"
+)
+
+// ApplicationOptions describes the configuration of an
+// anser.Application at runtime. Operations construct these options
+// and pass them to the Application before calling Setup and Run.
+type ApplicationOptions struct {
+	// DryRun, when set, runs the generation phase of an application
+	// but does not execute any of the generated migration jobs.
+	DryRun bool
+
+	// Limit caps the total number of migration jobs that an
+	// application will run. A value less than or equal to 0 means
+	// that there is no limit.
+	Limit int
+
+	// LockMode controls how Run behaves when the advisory migration
+	// lock is held by another process. The zero value behaves like
+	// LockModeBlock.
+	LockMode LockMode
+
+	// MaxConcurrentGenerators caps how many generators Run puts onto
+	// the queue at once during the generation phase. A value less
+	// than or equal to 0 means all generators are put onto the queue
+	// together, which is the historical behavior.
+	MaxConcurrentGenerators int
+
+	// MaxConcurrentMigrations makes Run wait, before putting each
+	// batch of generators onto the queue and again after adding that
+	// batch's migration jobs, until fewer than this many jobs are
+	// pending or running on the queue. All of one batch's migration
+	// jobs are added at once, so the queue can hold more than this
+	// many jobs while a batch runs. A value less than or equal to 0
+	// means there is no cap beyond Limit.
+	MaxConcurrentMigrations int
+}
@@ -0,0 +1,104 @@
+package anser
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// appliedMigrationRecord is the document persisted for each applied
+// generator. SeqNo is used to recover the order records were
+// inserted in, since Mongo does not otherwise guarantee find order.
+type appliedMigrationRecord struct {
+	ID        string    `bson:"_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+	SeqNo     int64     `bson:"seq_no"`
+}
+
+// appliedLogCounterID is the reserved _id of the single document in
+// the applied-log collection that holds the sequence-number counter,
+// incremented atomically by nextSeqNo. Queries over applied migration
+// records must exclude it.
+const appliedLogCounterID = "__anser_applied_log_seq__"
+
+type appliedLogCounter struct {
+	ID  string `bson:"_id"`
+	Seq int64  `bson:"seq"`
+}
+
+type mongoAppliedLog struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAppliedLog returns an AppliedMigrationLog backed by the
+// given collection, with one document per applied generator ID.
+func NewMongoAppliedLog(collection *mongo.Collection) AppliedMigrationLog {
+	return &mongoAppliedLog{collection: collection}
+}
+
+func (l *mongoAppliedLog) Applied(ctx context.Context) ([]string, error) {
+	cursor, err := l.collection.Find(
+		ctx,
+		bson.M{"_id": bson.M{"$ne": appliedLogCounterID}},
+		options.Find().SetSort(bson.M{"seq_no": 1}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding applied migration records")
+	}
+	defer cursor.Close(ctx)
+
+	ids := []string{}
+	for cursor.Next(ctx) {
+		record := appliedMigrationRecord{}
+		if err := cursor.Decode(&record); err != nil {
+			return nil, errors.Wrap(err, "decoding applied migration record")
+		}
+		ids = append(ids, record.ID)
+	}
+
+	return ids, errors.Wrap(cursor.Err(), "iterating applied migration records")
+}
+
+func (l *mongoAppliedLog) Record(ctx context.Context, id string) error {
+	seqNo, err := l.nextSeqNo(ctx)
+	if err != nil {
+		return errors.Wrap(err, "allocating sequence number")
+	}
+
+	_, err = l.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$setOnInsert": appliedMigrationRecord{ID: id, AppliedAt: time.Now(), SeqNo: seqNo}},
+		options.Update().SetUpsert(true),
+	)
+
+	return errors.Wrapf(err, "recording applied migration '%s'", id)
+}
+
+func (l *mongoAppliedLog) Remove(ctx context.Context, id string) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return errors.Wrapf(err, "removing applied migration record '%s'", id)
+}
+
+// nextSeqNo atomically increments and returns the applied-log's
+// sequence counter via a single findAndModify, so that concurrent
+// Record calls can never be allocated the same sequence number.
+func (l *mongoAppliedLog) nextSeqNo(ctx context.Context) (int64, error) {
+	res := l.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": appliedLogCounterID},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	counter := appliedLogCounter{}
+	if err := res.Decode(&counter); err != nil {
+		return 0, errors.Wrap(err, "incrementing applied migration sequence counter")
+	}
+
+	return counter.Seq, nil
+}
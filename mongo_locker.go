@@ -0,0 +1,219 @@
+package anser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lockLease is the document persisted in the lock collection for a
+// held lock. AcquiredAt and HeartbeatAt are always stamped with the
+// Mongo server's own clock (see serverNow and startHeartbeat's use of
+// $currentDate) rather than any individual process's local clock, so
+// that expiration is judged consistently regardless of clock skew.
+type lockLease struct {
+	Name        string    `bson:"_id"`
+	Owner       string    `bson:"owner"`
+	AcquiredAt  time.Time `bson:"acquired_at"`
+	HeartbeatAt time.Time `bson:"heartbeat_at"`
+}
+
+// mongoLocker is a MongoDB-backed MigrationLocker. It stores one
+// lease document per lock name in a dedicated collection with a
+// unique index on _id. Acquisition first atomically takes over an
+// expired lease document, if there is one, and otherwise inserts a
+// new one; the unique index makes that insert fail if another
+// process inserted first. A held lock is kept alive by a
+// background heartbeat goroutine; if a process crashes while holding
+// a lock, the lease simply stops being renewed and expires after ttl.
+type mongoLocker struct {
+	collection *mongo.Collection
+	owner      string
+	ttl        time.Duration
+	interval   time.Duration
+
+	mu   sync.Mutex
+	stop map[string]chan struct{}
+	held map[string]bool
+}
+
+// NewMongoLocker returns a MigrationLocker backed by the given
+// collection. owner should uniquely identify this process (for
+// example, a hostname and pid) so that lease documents can be
+// attributed during an incident. Callers are responsible for
+// ensuring collection has a unique index on _id, which is the
+// default for MongoDB collections.
+func NewMongoLocker(collection *mongo.Collection, owner string) MigrationLocker {
+	return &mongoLocker{
+		collection: collection,
+		owner:      owner,
+		ttl:        defaultLockLeaseTTL,
+		interval:   defaultLockHeartbeatInterval,
+		stop:       map[string]chan struct{}{},
+		held:       map[string]bool{},
+	}
+}
+
+// serverNow returns the connected Mongo server's current wall clock
+// time via the hello/isMaster handshake reply, so that lease
+// expiration can be judged against the server's clock rather than
+// this process's own, which may be skewed relative to it.
+func (l *mongoLocker) serverNow(ctx context.Context) (time.Time, error) {
+	reply := struct {
+		LocalTime time.Time `bson:"localTime"`
+	}{}
+
+	if err := l.collection.Database().RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&reply); err != nil {
+		return time.Time{}, errors.Wrap(err, "reading server time")
+	}
+
+	return reply.LocalTime, nil
+}
+
+// tryAcquire takes the lease document if it is missing or expired,
+// where "expired" is judged against the Mongo server's own clock
+// (fetched via serverNow) rather than this process's local clock, so
+// that clock skew between the two cannot cause a lease to be judged
+// expired early or late relative to when the server that owns the
+// TTL semantics would judge it.
+func (l *mongoLocker) tryAcquire(ctx context.Context, name string) (bool, error) {
+	now, err := l.serverNow(ctx)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	res := l.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"_id":          name,
+			"heartbeat_at": bson.M{"$lt": now.Add(-l.ttl)},
+		},
+		bson.M{
+			"$set": bson.M{"owner": l.owner, "acquired_at": now, "heartbeat_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(false),
+	)
+	if res.Err() == nil {
+		return true, nil
+	}
+	if !errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return false, errors.Wrap(res.Err(), "renewing expired lock lease")
+	}
+
+	_, err = l.collection.InsertOne(ctx, lockLease{
+		Name:        name,
+		Owner:       l.owner,
+		AcquiredAt:  now,
+		HeartbeatAt: now,
+	})
+	if err == nil {
+		return true, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+
+	return false, errors.Wrap(err, "inserting lock lease")
+}
+
+// TryAcquire implements LockTryAcquirer with a single, non-blocking
+// acquire attempt.
+func (l *mongoLocker) TryAcquire(ctx context.Context, name string) (bool, error) {
+	ok, err := l.tryAcquire(ctx, name)
+	if err != nil {
+		return false, errors.Wrapf(err, "acquiring lock '%s'", name)
+	}
+	if ok {
+		l.startHeartbeat(name)
+	}
+	return ok, nil
+}
+
+// Acquire implements MigrationLocker.
+func (l *mongoLocker) Acquire(ctx context.Context, name string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		ok, err := l.tryAcquire(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "acquiring lock '%s'", name)
+		}
+		if ok {
+			l.startHeartbeat(name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "canceled waiting for lock")
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// Release implements MigrationLocker.
+func (l *mongoLocker) Release(ctx context.Context, name string) error {
+	l.mu.Lock()
+	stop, ok := l.stop[name]
+	if ok {
+		close(stop)
+		delete(l.stop, name)
+		delete(l.held, name)
+	}
+	l.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": l.owner})
+	return errors.Wrapf(err, "releasing lock '%s'", name)
+}
+
+// startHeartbeat starts a background goroutine that renews the named
+// lease until Release is called or the lease is lost. If the caller
+// re-enters (Acquire or TryAcquire succeeds again for a name this
+// process already believes it holds, without an intervening Release),
+// held catches it and stops the previous heartbeat goroutine first;
+// otherwise that goroutine would be orphaned, since overwriting
+// l.stop[name] would drop the only reference able to stop it.
+func (l *mongoLocker) startHeartbeat(name string) {
+	stop := make(chan struct{})
+
+	l.mu.Lock()
+	if l.held[name] {
+		close(l.stop[name])
+	}
+	l.stop[name] = stop
+	l.held[name] = true
+	l.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				res, err := l.collection.UpdateOne(
+					context.Background(),
+					bson.M{"_id": name, "owner": l.owner},
+					bson.M{"$currentDate": bson.M{"heartbeat_at": true}},
+				)
+				if err == nil && res.MatchedCount == 0 {
+					// the lease is gone or owned by another process
+					return
+				}
+			}
+		}
+	}()
+}
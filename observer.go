@@ -0,0 +1,190 @@
+package anser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip"
+)
+
+// RunPhase identifies the broad stage of a Run invocation, reported
+// to a RunObserver via OnPhaseTransition.
+type RunPhase string
+
+const (
+	// PhaseGeneration covers putting generators onto the queue and
+	// waiting for them to produce migration jobs.
+	PhaseGeneration RunPhase = "generation"
+
+	// PhaseExecution covers running the generated migration jobs.
+	PhaseExecution RunPhase = "execution"
+
+	// PhaseComplete is reported once when a Run (including a dry
+	// run) finishes without error. It is not reported when Run
+	// returns an error.
+	PhaseComplete RunPhase = "complete"
+)
+
+// RunObserver receives structured callbacks as Application.Run moves
+// through its phases. Implementations should return quickly; Run
+// invokes observer methods synchronously and does not run generators
+// or migration jobs concurrently with the calls that report on them.
+//
+// The ctx passed to OnGeneratorStart is scoped to that generator: an
+// observer that wants to cancel a single misbehaving generator
+// without tearing down the whole Run can store it and cancel it
+// directly, or call Application.CancelGenerator.
+type RunObserver interface {
+	OnPhaseTransition(ctx context.Context, phase RunPhase)
+	OnGeneratorStart(ctx context.Context, generatorID string)
+	OnGeneratorComplete(ctx context.Context, generatorID string, err error)
+	OnMigrationJobComplete(ctx context.Context, jobID string, err error)
+}
+
+// noopObserver implements RunObserver with no-op methods, so that
+// other observers can embed it and only override the callbacks they
+// care about.
+type noopObserver struct{}
+
+func (noopObserver) OnPhaseTransition(context.Context, RunPhase)           {}
+func (noopObserver) OnGeneratorStart(context.Context, string)              {}
+func (noopObserver) OnGeneratorComplete(context.Context, string, error)    {}
+func (noopObserver) OnMigrationJobComplete(context.Context, string, error) {}
+
+// CancelGenerator cancels the per-generator context for the named
+// generator, if that generator is currently running as part of a
+// Run. It is a no-op if the generator is not currently running or
+// was never started with a per-generator context. CancelGenerator is
+// safe to call from a RunObserver callback or from another goroutine.
+func (a *Application) CancelGenerator(id string) {
+	a.generatorCancelsMu.Lock()
+	defer a.generatorCancelsMu.Unlock()
+
+	if cancel, ok := a.generatorCancels[id]; ok {
+		cancel()
+	}
+}
+
+func (a *Application) generatorContext(ctx context.Context, id string) context.Context {
+	genCtx, cancel := context.WithCancel(ctx)
+
+	a.generatorCancelsMu.Lock()
+	if a.generatorCancels == nil {
+		a.generatorCancels = map[string]context.CancelFunc{}
+	}
+	a.generatorCancels[id] = cancel
+	a.generatorCancelsMu.Unlock()
+
+	return genCtx
+}
+
+func (a *Application) clearGeneratorContext(id string) {
+	a.generatorCancelsMu.Lock()
+	defer a.generatorCancelsMu.Unlock()
+	delete(a.generatorCancels, id)
+}
+
+func (a *Application) observer() RunObserver {
+	if a.Observer != nil {
+		return a.Observer
+	}
+	return newDefaultObserver()
+}
+
+// defaultObserver reports on a Run's progress via grip, logging a
+// structured event per generator and per migration job, including the
+// generator's elapsed running time and the running counts of
+// succeeded and failed generators and migration jobs so far. It is
+// the RunObserver used when Application.Observer is unset.
+//
+// A defaultObserver is scoped to a single Run; Run constructs one via
+// newDefaultObserver each time it is called.
+type defaultObserver struct {
+	noopObserver
+
+	mu               sync.Mutex
+	generatorStarted map[string]time.Time
+	generatorsDone   jobCounts
+	jobsDone         jobCounts
+}
+
+// jobCounts tracks the running totals of succeeded and failed events
+// defaultObserver and jsonProgressObserver report alongside each
+// completion.
+type jobCounts struct {
+	Succeeded int
+	Failed    int
+}
+
+func newDefaultObserver() *defaultObserver {
+	return &defaultObserver{generatorStarted: map[string]time.Time{}}
+}
+
+func (o *defaultObserver) OnPhaseTransition(_ context.Context, phase RunPhase) {
+	grip.Noticef("anser run entering %s phase", phase)
+}
+
+func (o *defaultObserver) OnGeneratorStart(_ context.Context, generatorID string) {
+	o.mu.Lock()
+	o.generatorStarted[generatorID] = time.Now()
+	o.mu.Unlock()
+
+	grip.Infof("generator '%s' started", generatorID)
+}
+
+func (o *defaultObserver) OnGeneratorComplete(_ context.Context, generatorID string, err error) {
+	o.mu.Lock()
+	elapsed := time.Since(o.generatorStarted[generatorID])
+	delete(o.generatorStarted, generatorID)
+	counts := o.generatorsDone.record(err == nil)
+	o.mu.Unlock()
+
+	if err != nil {
+		grip.Errorf("generator '%s' failed after %s: %s (succeeded=%d failed=%d)",
+			generatorID, elapsed, err.Error(), counts.Succeeded, counts.Failed)
+		return
+	}
+	grip.Infof("generator '%s' completed in %s (succeeded=%d failed=%d)",
+		generatorID, elapsed, counts.Succeeded, counts.Failed)
+}
+
+func (o *defaultObserver) OnMigrationJobComplete(_ context.Context, jobID string, err error) {
+	o.mu.Lock()
+	counts := o.jobsDone.record(err == nil)
+	o.mu.Unlock()
+
+	if err != nil {
+		grip.Errorf("migration job '%s' failed: %s (succeeded=%d failed=%d)",
+			jobID, err.Error(), counts.Succeeded, counts.Failed)
+		return
+	}
+	grip.Infof("migration job '%s' completed (succeeded=%d failed=%d)",
+		jobID, counts.Succeeded, counts.Failed)
+}
+
+// record increments the appropriate counter and returns the updated
+// totals. Callers are responsible for holding whatever lock guards
+// the jobCounts.
+func (c *jobCounts) record(succeeded bool) jobCounts {
+	if succeeded {
+		c.Succeeded++
+	} else {
+		c.Failed++
+	}
+	return *c
+}
+
+// jsonProgressEvent is the shape written, one per line, by
+// NewJSONProgressObserver.
+type jsonProgressEvent struct {
+	Time        time.Time     `json:"time"`
+	Phase       RunPhase      `json:"phase,omitempty"`
+	GeneratorID string        `json:"generator_id,omitempty"`
+	JobID       string        `json:"job_id,omitempty"`
+	Event       string        `json:"event"`
+	Error       string        `json:"error,omitempty"`
+	Elapsed     time.Duration `json:"elapsed_ns,omitempty"`
+	Succeeded   int           `json:"succeeded,omitempty"`
+	Failed      int           `json:"failed,omitempty"`
+}
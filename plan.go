@@ -0,0 +1,87 @@
+package anser
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Estimator is an optional interface that a Generator may implement
+// to report the namespace it reads from and an approximate count of
+// the documents it will touch. Plan uses this to populate
+// MigrationPlanEntry.EstimatedDocumentCount without running the
+// generator; generators that do not implement Estimator report a
+// count of -1.
+type Estimator interface {
+	Namespace() (db string, collection string)
+	EstimatedDocumentCount(ctx context.Context) (int, error)
+}
+
+// MigrationPlanEntry describes a single generator as it would be
+// executed by Run, without actually generating or running any jobs.
+type MigrationPlanEntry struct {
+	GeneratorID            string
+	DependsOn              []string
+	EstimatedDocumentCount int
+}
+
+// Plan walks the generators Run would still execute — those not
+// already recorded in a.AppliedLog, if one is configured — and the
+// dependency network produced by Setup, and reports, for each, its
+// dependency edges and an estimated document count. Plan does not
+// enqueue any jobs and does not require a queue to be configured in
+// the Environment; it is safe to call repeatedly and concurrently
+// with Run.
+//
+// Plan returns an error if the application has not been configured
+// with Setup.
+func (a *Application) Plan(ctx context.Context) ([]MigrationPlanEntry, error) {
+	if !a.hasSetup {
+		return nil, errors.New("cannot plan an application before calling Setup")
+	}
+
+	pending, err := a.pendingGenerators(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "determining already-applied generators")
+	}
+
+	entries := make([]MigrationPlanEntry, 0, len(pending))
+	for _, generator := range pending {
+		entry := MigrationPlanEntry{
+			GeneratorID:            generator.ID(),
+			DependsOn:              generator.Dependency().Edges(),
+			EstimatedDocumentCount: -1,
+		}
+
+		if estimator, ok := generator.(Estimator); ok {
+			count, err := estimator.EstimatedDocumentCount(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "estimating document count for generator '%s'", entry.GeneratorID)
+			}
+			entry.EstimatedDocumentCount = count
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// HasPending reports whether any generator Run would still execute
+// would produce migration jobs. It is a thin wrapper around Plan
+// intended for health checks and CI gates that only need a yes/no
+// answer and cannot afford the cost of a dry run.
+func (a *Application) HasPending(ctx context.Context) (bool, error) {
+	plan, err := a.Plan(ctx)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	for _, entry := range plan {
+		if entry.EstimatedDocumentCount != 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
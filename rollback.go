@@ -0,0 +1,240 @@
+package anser
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mongodb/amboy"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// ReversibleGenerator is implemented by Generators that can produce a
+// compensating migration. Application.Rollback refuses to roll back
+// past any generator in its path that does not implement this
+// interface.
+type ReversibleGenerator interface {
+	Generator
+
+	// Inverse returns a generator that undoes the effect of this
+	// generator's migrations.
+	Inverse() (Generator, error)
+}
+
+// Rollback undoes previously applied migrations in reverse
+// dependency order: the generator identified by target and every
+// applied generator that transitively depends on it, dependents
+// first and target last (see reverseApplyPath). Applied generators
+// that do not depend on target are left in place. It requires that
+// a.AppliedLog be configured; without a record of what actually ran,
+// reversing the static Generators list would be unsafe.
+//
+// Rollback refuses to proceed, and makes no changes, if target has
+// not been applied or if any generator to be undone is not a
+// ReversibleGenerator.
+func (a *Application) Rollback(ctx context.Context, target string) error {
+	if !a.hasSetup {
+		return errors.New("cannot roll back an application before calling Setup")
+	}
+
+	if a.AppliedLog == nil {
+		return errors.New("cannot roll back an application without an AppliedMigrationLog")
+	}
+
+	applied, err := a.AppliedLog.Applied(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching applied migrations")
+	}
+
+	byID := map[string]Generator{}
+	edgesOf := map[string][]string{}
+	for _, generator := range a.Generators {
+		byID[generator.ID()] = generator
+		edgesOf[generator.ID()] = generator.Dependency().Edges()
+	}
+
+	path, err := reverseApplyPath(edgesOf, applied, target)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	inverses := make([]Generator, 0, len(path))
+	for _, id := range path {
+		generator, ok := byID[id]
+		if !ok {
+			return errors.Errorf("applied generator '%s' is not configured on this application", id)
+		}
+
+		reversible, ok := generator.(ReversibleGenerator)
+		if !ok {
+			return errors.Errorf("generator '%s' is not reversible, refusing to roll back past it", id)
+		}
+
+		inverse, err := reversible.Inverse()
+		if err != nil {
+			return errors.Wrapf(err, "building inverse for generator '%s'", id)
+		}
+		inverses = append(inverses, inverse)
+	}
+
+	queue, err := a.env.GetQueue()
+	if err != nil {
+		return errors.Wrap(err, "getting queue")
+	}
+
+	catcher := grip.NewCatcher()
+	for _, inverse := range inverses {
+		catcher.Add(queue.Put(ctx, inverse))
+	}
+	if catcher.HasErrors() {
+		return errors.Wrap(catcher.Resolve(), "adding rollback generation jobs")
+	}
+
+	amboy.WaitInterval(ctx, queue, time.Second)
+	if ctx.Err() != nil {
+		return errors.New("rollback operation canceled")
+	}
+
+	numMigrations, err := addMigrationJobs(ctx, queue, false, 0)
+	if err != nil {
+		return errors.Wrap(err, "adding generated rollback jobs")
+	}
+
+	grip.Noticef("running %d rollback jobs for %d generators", numMigrations, len(inverses))
+	amboy.WaitInterval(ctx, queue, time.Second)
+	if ctx.Err() != nil {
+		return errors.New("rollback operation canceled")
+	}
+
+	if err := amboy.ResolveErrors(ctx, queue); err != nil {
+		return errors.Wrap(err, "running rollback jobs")
+	}
+
+	for _, id := range path {
+		catcher.Add(a.AppliedLog.Remove(ctx, id))
+	}
+
+	return errors.Wrap(catcher.Resolve(), "updating applied migration log after rollback")
+}
+
+// reverseApplyPath returns the IDs of the applied generators that
+// must be undone to roll back to target: target itself, plus every
+// applied generator that transitively depends on it (directly or
+// through a chain of other generators), in the order their inverses
+// should run — dependents before the things they depend on, ending
+// with target last.
+//
+// edgesOf maps a generator ID to the IDs of the generators it depends
+// on, the same edges Setup feeds into the dependency network.
+// Generators that are applied but unrelated to target (neither it nor
+// any transitive dependent of it) are left untouched, even if they
+// happen to sort after target in some topological order of the whole
+// network: topological order alone does not imply a dependency
+// relationship between two independent nodes.
+//
+// It errors if target was never applied.
+func reverseApplyPath(edgesOf map[string][]string, applied []string, target string) ([]string, error) {
+	appliedSet := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		appliedSet[id] = true
+	}
+	if !appliedSet[target] {
+		return nil, errors.Errorf("generator '%s' has not been applied", target)
+	}
+
+	dependents := reverseEdges(edgesOf)
+
+	affected := map[string]bool{}
+	queue := []string{target}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if affected[id] {
+			continue
+		}
+		affected[id] = true
+		for _, dependent := range dependents[id] {
+			if !affected[dependent] {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	subset := map[string]bool{}
+	for id := range affected {
+		if appliedSet[id] {
+			subset[id] = true
+		}
+	}
+
+	order := topoSortSubset(edgesOf, subset)
+
+	path := make([]string, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		path = append(path, order[i])
+	}
+
+	return path, nil
+}
+
+// reverseEdges inverts a dependency-edge map (id -> the IDs it
+// depends on) into a dependents map (id -> the IDs that depend on
+// it).
+func reverseEdges(edgesOf map[string][]string) map[string][]string {
+	dependents := map[string][]string{}
+	for id, edges := range edgesOf {
+		for _, dependsOn := range edges {
+			dependents[dependsOn] = append(dependents[dependsOn], id)
+		}
+	}
+	return dependents
+}
+
+// topoSortSubset returns the members of subset in dependency order
+// (a generator before anything in subset that depends on it), using
+// only the edges between members of subset. Ties are broken by ID so
+// the result is deterministic.
+func topoSortSubset(edgesOf map[string][]string, subset map[string]bool) []string {
+	inDegree := make(map[string]int, len(subset))
+	forward := map[string][]string{}
+	for id := range subset {
+		inDegree[id] = 0
+	}
+	for id := range subset {
+		for _, dependsOn := range edgesOf[id] {
+			if !subset[dependsOn] {
+				continue
+			}
+			forward[dependsOn] = append(forward[dependsOn], id)
+			inDegree[id]++
+		}
+	}
+
+	ready := make([]string, 0, len(subset))
+	for id := range subset {
+		if inDegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(subset))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+
+		next := forward[id]
+		sort.Strings(next)
+		for _, dependent := range next {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+				sort.Strings(ready)
+			}
+		}
+	}
+
+	return order
+}
@@ -0,0 +1,51 @@
+package anser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReverseApplyPathUsesDependencyOrderNotApplyOrder(t *testing.T) {
+	// c depends on a; b is independent of both. Rolling back to "a"
+	// must undo c before a, and must not touch b at all, even though b
+	// was recorded after c in the applied log.
+	edgesOf := map[string][]string{"c": {"a"}}
+	applied := []string{"a", "b", "c"}
+
+	path, err := reverseApplyPath(edgesOf, applied, "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	want := []string{"c", "a"}
+	if !reflect.DeepEqual(path, want) {
+		t.Fatalf("expected %v, got %v", want, path)
+	}
+}
+
+func TestReverseApplyPathWalksTransitiveDependents(t *testing.T) {
+	// c depends on b, b depends on a. Rolling back to "a" must undo c
+	// and b as well, in dependency order.
+	edgesOf := map[string][]string{
+		"b": {"a"},
+		"c": {"b"},
+	}
+	applied := []string{"a", "b", "c"}
+
+	path, err := reverseApplyPath(edgesOf, applied, "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	want := []string{"c", "b", "a"}
+	if !reflect.DeepEqual(path, want) {
+		t.Fatalf("expected %v, got %v", want, path)
+	}
+}
+
+func TestReverseApplyPathErrorsIfTargetNotApplied(t *testing.T) {
+	edgesOf := map[string][]string{"b": {"a"}}
+	if _, err := reverseApplyPath(edgesOf, []string{"a"}, "b"); err == nil {
+		t.Fatal("expected an error for a target that was never applied")
+	}
+}